@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	stdx509 "crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -13,7 +15,6 @@ import (
 	"net/http"
 	"os"
 	"regexp"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -22,12 +23,23 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	ct "github.com/google/certificate-transparency-go"
-	"github.com/google/certificate-transparency-go/client"
-	"github.com/google/certificate-transparency-go/jsonclient"
 	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/tls"
 	"github.com/google/certificate-transparency-go/x509"
+	"github.com/psanford/cert-monitor-lambda/pkg/gossip"
+	"github.com/psanford/cert-monitor-lambda/pkg/logclient"
+	"github.com/psanford/cert-monitor-lambda/pkg/monitor"
+	"github.com/psanford/cert-monitor-lambda/pkg/notifiers"
+	"github.com/psanford/cert-monitor-lambda/pkg/policy"
+	"github.com/psanford/cert-monitor-lambda/pkg/storage/index"
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
 )
 
+// invocationMargin is reserved at the end of every Lambda invocation for
+// writing results back to S3 once log fetching stops.
+const invocationMargin = 10 * time.Second
+
 func main() {
 	s := newServer()
 	lambda.Start(s.Handler)
@@ -38,15 +50,28 @@ func newServer() *server {
 }
 
 type server struct {
-	bucket   string
-	s3       *s3.Client
-	conf     *Config
-	patterns []*regexp.Regexp
+	bucket       string
+	s3           *s3.Client
+	conf         *Config
+	domainPolicy *policy.Policy
+	patterns     []*regexp.Regexp
+	notifyRoutes []notifyRoute
+	certIndex    *index.Index
+	gossip       *gossip.Store
 }
 
-func (s *server) Handler(evt events.CloudWatchEvent) error {
+func (s *server) Handler(ctx context.Context, evt events.CloudWatchEvent) error {
 	lgr := slog.With()
-	ctx := context.Background()
+
+	// Leave ourselves a margin before the Lambda runtime kills the
+	// invocation so in-flight S3 writes and the final state put have time
+	// to complete; chunked log fetches stop dispatching new work once this
+	// deadline passes rather than running until the hard cutoff.
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-invocationMargin))
+		defer cancel()
+	}
 
 	bucketName := os.Getenv("CERT_MONITOR_BUCKET")
 	if bucketName == "" {
@@ -63,6 +88,8 @@ func (s *server) Handler(evt events.CloudWatchEvent) error {
 	s3client := s3.NewFromConfig(cfg)
 
 	s.s3 = s3client
+	s.certIndex = &index.Index{S3: s3client, Bucket: s.bucket}
+	s.gossip = &gossip.Store{S3: s3client, Bucket: s.bucket}
 
 	confResult := make(chan *Config)
 	stateResult := make(chan LogStates)
@@ -120,6 +147,18 @@ func (s *server) Handler(evt events.CloudWatchEvent) error {
 
 	s.patterns = patterns
 
+	domainPolicy, err := policy.New(s.conf.Domains)
+	if err != nil {
+		return fmt.Errorf("build domain policy err: %w", err)
+	}
+	s.domainPolicy = domainPolicy
+
+	notifyRoutes, err := s.buildNotifyRoutes(cfg, s.conf.Notify)
+	if err != nil {
+		return fmt.Errorf("build notify routes err: %w", err)
+	}
+	s.notifyRoutes = notifyRoutes
+
 	resultChan := make(chan *LogState)
 	var logCount int
 
@@ -139,17 +178,45 @@ func (s *server) Handler(evt events.CloudWatchEvent) error {
 			}
 
 			logCount++
-			go func() {
-				result, err := s.processLog(ctx, lgr, state)
+			go func(log loglist3.Log, state *LogState) {
+				result, err := s.processLog(ctx, lgr, log, state)
 				if err != nil {
 					errorChan <- err
 				} else {
 					resultChan <- result
 				}
-			}()
+			}(*log, state)
 		}
 	}
 
+	// ExtraLogs covers logs fetchLogList's published list doesn't know
+	// about at all. The loglist3.Log argument to processLog is only used
+	// by verifySTHSignature, which is skipped for anything but "rfc6962"
+	// logs, so the zero value below is fine for the static-ct logs
+	// ExtraLogs exists to support. An RFC 6962 log has no key to verify
+	// against without a real loglist3.Log entry, so it belongs in
+	// log_list.json, not here.
+	for _, extra := range s.conf.ExtraLogs {
+		state := states[extra.URL]
+		if state == nil {
+			state = &LogState{
+				URL:         extra.URL,
+				Operator:    extra.Operator,
+				Description: extra.Description,
+			}
+		}
+
+		logCount++
+		go func(state *LogState) {
+			result, err := s.processLog(ctx, lgr, loglist3.Log{}, state)
+			if err != nil {
+				errorChan <- err
+			} else {
+				resultChan <- result
+			}
+		}(state)
+	}
+
 	lgr.Info("waiting for results", "log_count", logCount)
 
 	for i := 0; i < logCount; i++ {
@@ -180,16 +247,17 @@ func (s *server) Handler(evt events.CloudWatchEvent) error {
 	return nil
 }
 
-func (s *server) processLog(ctx context.Context, lgr *slog.Logger, state *LogState) (*LogState, error) {
+func (s *server) processLog(ctx context.Context, lgr *slog.Logger, log loglist3.Log, state *LogState) (*LogState, error) {
 	lgr = lgr.With("log", state.URL)
 	lgr.Info("fetch log")
 	var entriesSeen int
 	defer func() {
 		lgr.Info("fetch log done", "entry_count", entriesSeen)
 	}()
-	lc, err := client.New(state.URL, http.DefaultClient, jsonclient.Options{})
+	logType := logclient.DetectType(state.URL, s.conf.LogTypes)
+	lc, err := logclient.New(state.URL, logType, http.DefaultClient)
 	if err != nil {
-		lgr.Error("new client err", "err", err)
+		lgr.Error("new client err", "err", err, "log_type", logType)
 		return nil, err
 	}
 
@@ -199,95 +267,304 @@ func (s *server) processLog(ctx context.Context, lgr *slog.Logger, state *LogSta
 		return state, nil
 	}
 
+	// Static-CT logs have no TreeHeadSignature to check -- their checkpoint
+	// is authenticated by a different, note-based signature scheme that
+	// isn't verified here at all yet; see logclient.StaticTileClient.GetSTH's
+	// doc comment for what that gap actually means.
+	if logType == "rfc6962" {
+		if err := verifySTHSignature(log, sth); err != nil {
+			lgr.Error("sth signature verification failed", "err", err)
+			if alertErr := s.writeSplitViewAlert(ctx, state, "sth signature verification failed", err, sth); alertErr != nil {
+				lgr.Error("write split view alert err", "err", alertErr)
+			}
+			return state, nil
+		}
+	}
+
 	if state.LastFetched == 0 {
 		lgr.Info("init state")
 		state.LastFetched = sth.TreeSize
 		state.LastFetchedTime = time.Now()
+		state.TreeSize = sth.TreeSize
+		state.SHA256RootHash = sth.SHA256RootHash[:]
+		state.TreeHeadSignature = sth.TreeHeadSignature
+		if err := s.gossip.Publish(ctx, state.URL, sth); err != nil {
+			lgr.Error("gossip publish err", "err", err)
+		}
+		return state, nil
+	}
+
+	// verifyConsistency runs even when the tree size hasn't grown: its
+	// equal-size branch is what catches a log serving a stable size but a
+	// different root hash than before, i.e. a split view that never
+	// changes the log's reported size. Checking "not changed" first would
+	// skip that check entirely for exactly the logs it matters most for.
+	if err := s.verifyConsistency(ctx, lc, state, sth); err != nil {
+		lgr.Error("consistency proof verification failed", "err", err)
+		if alertErr := s.writeSplitViewAlert(ctx, state, "consistency proof verification failed", err, sth); alertErr != nil {
+			lgr.Error("write split view alert err", "err", alertErr)
+		}
 		return state, nil
 	}
 
+	// The STH itself is now trusted as our new consistency checkpoint even
+	// if we don't finish ingesting every entry under it this invocation;
+	// ingestion progress is tracked separately via LastFetched below.
+	state.TreeSize = sth.TreeSize
+	state.SHA256RootHash = sth.SHA256RootHash[:]
+	state.TreeHeadSignature = sth.TreeHeadSignature
+
+	// Publish every verified STH, not just ones where ingestion makes
+	// progress below, so the durable gossip record matches what
+	// verifyConsistency actually checked this log against.
+	if err := s.gossip.Publish(ctx, state.URL, sth); err != nil {
+		lgr.Error("gossip publish err", "err", err)
+	}
+
 	if sth.TreeSize == state.LastFetched {
 		lgr.Info("log not changed")
 		return state, nil
 	}
 
 	start := int64(state.LastFetched + 1)
-	rawEntries, err := lc.GetRawEntries(ctx, start, int64(sth.TreeSize))
+	fetcher := monitor.Fetcher{}
+	reached, err := fetcher.Run(ctx, start, int64(sth.TreeSize), func(ctx context.Context, start, end int64) ([]ct.LeafEntry, error) {
+		return lc.GetRawEntries(ctx, start, end)
+	}, func(idx int64, entry *ct.LeafEntry) error {
+		entriesSeen++
+		return s.handleEntry(ctx, lgr, state.URL, idx, entry)
+	})
 	if err != nil {
-		lgr.Error("get raw entries err", "err", err)
-		return state, nil
+		lgr.Error("chunked fetch err", "err", err, "reached", reached)
 	}
 
-	for i, entry := range rawEntries.Entries {
-		entriesSeen++
-		index := start + int64(i)
-		logEntry, err := ct.LogEntryFromLeaf(index, &entry)
-		if x509.IsFatal(err) {
-			lgr.Error("get parse log err", "err", err)
-			continue
-		}
+	state.LastFetched = uint64(reached - 1)
+	state.LastFetchedTime = time.Now()
+	return state, nil
+}
 
-		certType := "cert"
-		var cert *x509.Certificate
-		if logEntry.X509Cert != nil {
-			cert = logEntry.X509Cert
-		}
+// handleEntry decodes a single raw leaf entry and, if it matches the
+// configured domains or patterns, writes it under certs/ in the bucket and
+// fans it out to any notification sinks configured for that match.
+func (s *server) handleEntry(ctx context.Context, lgr *slog.Logger, logURL string, idx int64, entry *ct.LeafEntry) error {
+	logEntry, err := ct.LogEntryFromLeaf(idx, entry)
+	if x509.IsFatal(err) {
+		lgr.Error("get parse log err", "err", err)
+		return nil
+	}
 
-		if s.conf.IncludePreCerts && logEntry.Precert != nil {
-			certType = "precert"
-			cert = logEntry.Precert.TBSCertificate
-		}
+	certType := "cert"
+	var cert *x509.Certificate
+	if logEntry.X509Cert != nil {
+		cert = logEntry.X509Cert
+	}
 
-		if cert != nil {
-			if match, name, matchStr := s.nameMatches(cert); match {
-				b := make([]byte, 16)
-				rand.Read(b)
-				bstr := base64.URLEncoding.EncodeToString(b)
-				key := fmt.Sprintf("certs/%s-%s-%s.json", time.Now().Format(time.RFC3339Nano), bstr, name)
-				lgr.Info("match", "type", certType, "rule", matchStr, "name", name, "key", key)
+	if s.conf.IncludePreCerts && logEntry.Precert != nil {
+		certType = "precert"
+		cert = logEntry.Precert.TBSCertificate
+	}
 
-				jsonTxt, err := json.Marshal(entry)
-				if err != nil {
-					lgr.Error("marshal json err", "key", key, "err", err)
-					return nil, err
-				}
+	if cert == nil {
+		return nil
+	}
 
-				_, err = s.s3.PutObject(ctx, &s3.PutObjectInput{
-					Bucket: &s.bucket,
-					Key:    &key,
-					Body:   bytes.NewBuffer(jsonTxt),
-				})
-				if err != nil {
-					lgr.Error("put cert err", "key", key, "err", err)
-					return nil, fmt.Errorf("s3 put object err: %w", err)
-				}
-			}
+	match, name, matchStr := s.nameMatches(cert)
+	if !match {
+		return nil
+	}
+
+	fingerprint := fmt.Sprintf("%x", sha256.Sum256(cert.Raw))
+
+	// Reserve marks fingerprint seen as part of the same check, so two
+	// goroutines racing on the same fingerprint (the same certificate
+	// landing in different logs, say) can't both pass this check
+	// before either writes the cert or notifies -- see its doc comment.
+	seen, err := s.certIndex.Reserve(ctx, fingerprint)
+	if err != nil {
+		lgr.Error("index lookup err", "err", err)
+	} else if seen {
+		lgr.Info("duplicate match, skipping", "rule", matchStr, "name", name, "fingerprint", fingerprint)
+		return nil
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	bstr := base64.URLEncoding.EncodeToString(b)
+	key := fmt.Sprintf("certs/%s-%s-%s.json", time.Now().Format(time.RFC3339Nano), bstr, name)
+	lgr.Info("match", "type", certType, "rule", matchStr, "name", name, "key", key)
+
+	jsonTxt, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal json err key=%s: %w", key, err)
+	}
+
+	_, err = s.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+		Body:   bytes.NewBuffer(jsonTxt),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 put object err key=%s: %w", key, err)
+	}
+
+	now := time.Now()
+	if err := s.certIndex.Record(ctx, index.Record{
+		Fingerprint: fingerprint,
+		Domain:      name,
+		Issuer:      cert.Issuer.CommonName,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		LogURL:      logURL,
+		S3Key:       key,
+		Time:        now,
+	}); err != nil {
+		lgr.Error("index record err", "err", err)
+	}
+
+	evt := notifiers.MatchEvent{
+		LogURL:      logURL,
+		MatchedRule: matchStr,
+		SANName:     name,
+		CertType:    certType,
+		Fingerprint: fingerprint,
+		S3Key:       key,
+		Issuer:      cert.Issuer.CommonName,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+	}
+	s.notifyMatch(ctx, lgr, evt, matchedDomainFromRule(matchStr))
+
+	return nil
+}
+
+// verifySTHSignature checks that sth was actually signed by the log's public
+// key, as published in loglist3. This stops us from ingesting entries served
+// by an impostor sitting in front of the real log.
+func verifySTHSignature(log loglist3.Log, sth *ct.SignedTreeHead) error {
+	pubKey, err := stdx509.ParsePKIXPublicKey(log.Key)
+	if err != nil {
+		return fmt.Errorf("parse log public key err: %w", err)
+	}
+
+	data, err := ct.SerializeSTHSignatureInput(*sth)
+	if err != nil {
+		return fmt.Errorf("serialize sth signature input err: %w", err)
+	}
+
+	if err := tls.VerifySignature(pubKey, data, tls.DigitallySigned(sth.TreeHeadSignature)); err != nil {
+		return fmt.Errorf("verify sth signature err: %w", err)
+	}
+
+	return nil
+}
+
+// verifyConsistency checks that sth is consistent with the last STH we
+// verified for this log using the standard Merkle consistency proof
+// algorithm. A log that has forked, or that is serving a "split view" to
+// different clients, will fail this check.
+func (s *server) verifyConsistency(ctx context.Context, lc logclient.Client, state *LogState, sth *ct.SignedTreeHead) error {
+	if state.TreeSize == 0 {
+		// We have no prior verified STH to compare against (e.g. state was
+		// loaded from an older version that didn't track this). Trust this
+		// STH as the new baseline rather than refusing to make progress.
+		return nil
+	}
+
+	if sth.TreeSize < state.TreeSize {
+		return fmt.Errorf("log tree size shrank from %d to %d", state.TreeSize, sth.TreeSize)
+	}
+
+	if sth.TreeSize == state.TreeSize {
+		if !bytes.Equal(sth.SHA256RootHash[:], state.SHA256RootHash) {
+			return fmt.Errorf("root hash changed at fixed tree size %d", sth.TreeSize)
 		}
+		return nil
 	}
 
-	state.LastFetched = sth.TreeSize
-	state.LastFetchedTime = time.Now()
-	return state, nil
+	consistencyProof, err := lc.GetSTHConsistency(ctx, int64(state.TreeSize), int64(sth.TreeSize))
+	if err != nil {
+		return fmt.Errorf("get sth consistency err: %w", err)
+	}
+
+	if err := proof.VerifyConsistency(rfc6962.DefaultHasher, state.TreeSize, sth.TreeSize, consistencyProof, state.SHA256RootHash, sth.SHA256RootHash[:]); err != nil {
+		return fmt.Errorf("verify consistency proof err: %w", err)
+	}
+
+	return nil
+}
+
+// writeSplitViewAlert records that a log failed verification so a human can
+// investigate, rather than silently dropping the problem. We deliberately do
+// not advance state.LastFetched when this fires.
+func (s *server) writeSplitViewAlert(ctx context.Context, state *LogState, reason string, cause error, sth *ct.SignedTreeHead) error {
+	alert := struct {
+		LogURL      string             `json:"log_url"`
+		Time        time.Time          `json:"time"`
+		Reason      string             `json:"reason"`
+		Cause       string             `json:"cause"`
+		LastState   *LogState          `json:"last_state"`
+		ObservedSTH *ct.SignedTreeHead `json:"observed_sth,omitempty"`
+	}{
+		LogURL:      state.URL,
+		Time:        time.Now(),
+		Reason:      reason,
+		Cause:       cause.Error(),
+		LastState:   state,
+		ObservedSTH: sth,
+	}
+
+	jsonTxt, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("marshal alert err: %w", err)
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	bstr := base64.URLEncoding.EncodeToString(b)
+	key := fmt.Sprintf("alerts/%s-%s-split-view.json", time.Now().Format(time.RFC3339Nano), bstr)
+
+	_, err = s.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+		Body:   bytes.NewBuffer(jsonTxt),
+	})
+	if err != nil {
+		return fmt.Errorf("put alert object err: %w", err)
+	}
+
+	return nil
 }
 
 func (s *server) nameMatches(c *x509.Certificate) (bool, string, string) {
-	for _, name := range c.DNSNames {
-		for _, lookingFor := range s.conf.Domains {
-			if strings.HasSuffix(name, lookingFor) {
-				if name == lookingFor || strings.HasSuffix(name, "."+lookingFor) {
-					return true, name, fmt.Sprintf("domain-%s", lookingFor)
-				}
-			}
-		}
+	if results := s.domainPolicy.Match(c); len(results) > 0 {
+		r := results[0]
+		return true, r.SAN, fmt.Sprintf("domain-%s", r.Rule)
+	}
+
+	for _, name := range sanStrings(c) {
 		for _, pattern := range s.patterns {
-			if pattern.Match([]byte(name)) {
+			if pattern.MatchString(name) {
 				return true, name, fmt.Sprintf("pattern-%s", pattern)
 			}
 		}
 	}
 
 	return false, "", ""
+}
 
+// sanStrings flattens every SAN on c -- DNS names, IP addresses and URIs --
+// into their string form so callers can match against all of them
+// uniformly.
+func sanStrings(c *x509.Certificate) []string {
+	out := make([]string, 0, len(c.DNSNames)+len(c.IPAddresses)+len(c.URIs))
+	out = append(out, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		out = append(out, ip.String())
+	}
+	for _, uri := range c.URIs {
+		out = append(out, uri.String())
+	}
+	return out
 }
 
 type LogStates map[string]*LogState
@@ -315,11 +592,24 @@ func (s *server) fetchLogState(ctx context.Context) (LogStates, error) {
 }
 
 type LogState struct {
-	URL             string    `json:"url"`
-	Operator        string    `json:"operator"`
-	Description     string    `json:"description"`
+	URL         string `json:"url"`
+	Operator    string `json:"operator"`
+	Description string `json:"description"`
+	// LastFetched is the index of the last entry ingested, advanced in
+	// monitor.Fetcher's chunk size. A Lambda invocation that hits its
+	// deadline mid-log leaves this at the end of the last chunk it fully
+	// processed, so the next invocation's chunked fetch resumes exactly
+	// there without any separate in-flight bookkeeping.
 	LastFetched     uint64    `json:"last_fetched"`
 	LastFetchedTime time.Time `json:"last_fetched_time"`
+
+	// TreeSize, SHA256RootHash and TreeHeadSignature record the most
+	// recently verified STH for this log. They are used as the "old" STH
+	// in the consistency proof check on the next invocation, so that the
+	// log is never allowed to rewrite history out from under us.
+	TreeSize          uint64             `json:"tree_size"`
+	SHA256RootHash    []byte             `json:"sha256_root_hash"`
+	TreeHeadSignature ct.DigitallySigned `json:"tree_head_signature"`
 }
 
 func fetchLogList() (*loglist3.LogList, error) {
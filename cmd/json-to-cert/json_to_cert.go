@@ -17,6 +17,11 @@ import (
 var format = flag.String("format", "text", "text|json|pem")
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "query" {
+		runQuery(os.Args[2:])
+		return
+	}
+
 	flag.Parse()
 
 	var printFunc func(cert *ctx509.Certificate)
@@ -33,7 +38,7 @@ func main() {
 
 	args := flag.Args()
 	if len(args) < 1 {
-		log.Fatalf("usage: %s <cert.json>", os.Args[0])
+		log.Fatalf("usage: %s <cert.json> | %s query [flags]", os.Args[0], os.Args[0])
 	}
 
 	f, err := os.Open(args[0])
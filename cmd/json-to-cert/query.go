@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	ct "github.com/google/certificate-transparency-go"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/psanford/cert-monitor-lambda/pkg/storage/index"
+)
+
+// runQuery implements the `query` subcommand: it searches cert-monitor-lambda's
+// persistent certificate index for records matching the given filters and
+// prints each matching certificate using the same printText/printJson/printPem
+// helpers as single-file mode.
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	bucket := fs.String("bucket", os.Getenv("CERT_MONITOR_BUCKET"), "S3 bucket cert-monitor-lambda writes to")
+	domain := fs.String("domain", "", "only show certs whose matched SAN contains this substring")
+	issuer := fs.String("issuer", "", "only show certs whose issuer contains this substring")
+	since := fs.String("since", "", "only show certs observed after this RFC3339 time")
+	until := fs.String("until", "", "only show certs observed before this RFC3339 time")
+	format := fs.String("format", "text", "text|json|pem")
+	fs.Parse(args)
+
+	if *bucket == "" {
+		log.Fatalf("-bucket (or CERT_MONITOR_BUCKET) is required")
+	}
+
+	var printFunc func(cert *ctx509.Certificate)
+	switch *format {
+	case "text":
+		printFunc = printText
+	case "json":
+		printFunc = printJson
+	case "pem":
+		printFunc = printPem
+	default:
+		log.Fatalf("invalid -format flag")
+	}
+
+	filter := index.Filter{Domain: *domain, Issuer: *issuer}
+	if *since != "" {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			log.Fatalf("invalid -since: %s", err)
+		}
+		filter.Since = t
+	}
+	if *until != "" {
+		t, err := time.Parse(time.RFC3339, *until)
+		if err != nil {
+			log.Fatalf("invalid -until: %s", err)
+		}
+		filter.Until = t
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s3client := s3.NewFromConfig(cfg)
+
+	ix := &index.Index{S3: s3client, Bucket: *bucket}
+	records, err := ix.Query(ctx, filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, rec := range records {
+		cert, err := fetchIndexedCert(ctx, s3client, *bucket, rec.S3Key)
+		if err != nil {
+			log.Printf("fetch cert %s err: %s", rec.S3Key, err)
+			continue
+		}
+		printFunc(cert)
+	}
+}
+
+// fetchIndexedCert loads the raw leaf entry an index record points at and
+// decodes it the same way single-file mode does.
+func fetchIndexedCert(ctx context.Context, s3client *s3.Client, bucket, key string) (*ctx509.Certificate, error) {
+	resp, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rawEntry ct.LeafEntry
+	if err := json.NewDecoder(resp.Body).Decode(&rawEntry); err != nil {
+		return nil, err
+	}
+
+	logEntry, err := ct.LogEntryFromLeaf(0, &rawEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	if logEntry.X509Cert != nil {
+		return logEntry.X509Cert, nil
+	}
+	if logEntry.Precert != nil {
+		return logEntry.Precert.TBSCertificate, nil
+	}
+	return nil, fmt.Errorf("no certificate found in entry %s", key)
+}
@@ -19,6 +19,62 @@ type Config struct {
 
 	// Coollect pre-certificate entries
 	IncludePreCerts bool `toml:"include_pre_certs"`
+
+	// Notify configures where matches are sent. Each [[notify]] entry is
+	// its own sink; a match fans out to every entry whose Domains filter
+	// (or lack of one) covers the domain that matched.
+	Notify []NotifyRule `toml:"notify"`
+
+	// LogTypes overrides logclient.DetectType's protocol guess for a log
+	// URL, keyed by the log's URL as it appears in loglist3. This is how a
+	// static-CT ("tile") log gets monitored before loglist3 gains a field
+	// that identifies it as such, or for a private log that never appears
+	// in the published list at all. Valid values are "rfc6962" (the
+	// default) and "static-ct".
+	LogTypes map[string]string `toml:"log_types"`
+
+	// ExtraLogs are monitored alongside whatever fetchLogList pulls from
+	// the published log_list.json, for logs that don't appear there at
+	// all -- a private static-CT log, or a new one still working through
+	// Chrome/CCADB inclusion. Each entry's URL should also have a
+	// corresponding LogTypes entry if it isn't an RFC 6962 log.
+	ExtraLogs []ExtraLog `toml:"extra_logs"`
+}
+
+// ExtraLog describes a log to monitor that isn't present in loglist3's
+// published list.
+type ExtraLog struct {
+	URL         string `toml:"url"`
+	Operator    string `toml:"operator"`
+	Description string `toml:"description"`
+}
+
+// NotifyRule configures a single notification sink. Type selects which of
+// the fields below are used:
+//
+//	"webhook" - URL, Secret
+//	"slack"   - URL
+//	"sns"     - TopicARN
+//	"ses"     - From, To
+type NotifyRule struct {
+	Type string `toml:"type"`
+
+	// Domains restricts this rule to matches against the listed entries
+	// from Config.Domains. An empty list means "notify for every match",
+	// including pattern matches, which have no associated domain.
+	Domains []string `toml:"domains"`
+
+	URL    string `toml:"url"`
+	Secret string `toml:"secret"`
+
+	TopicARN string `toml:"topic_arn"`
+
+	From string   `toml:"from"`
+	To   []string `toml:"to"`
+
+	// DedupWindowSeconds is how long a fingerprint already delivered to
+	// this sink is suppressed for. Defaults to 24 hours if zero.
+	DedupWindowSeconds int `toml:"dedup_window_seconds"`
 }
 
 func (s *server) loadConfig(ctx context.Context) (*Config, error) {
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/psanford/cert-monitor-lambda/pkg/notifiers"
+)
+
+const defaultDedupWindow = 24 * time.Hour
+
+// notifyRoute binds a configured sink to the domains it should fire for and
+// to its own dedup state, so a match against multiple rules only notifies
+// the sinks that asked to hear about that particular domain.
+type notifyRoute struct {
+	notifier notifiers.Notifier
+	domains  map[string]struct{} // empty set means "notify for every match"
+	dedup    *notifiers.Dedup
+}
+
+func (s *server) buildNotifyRoutes(cfg aws.Config, rules []NotifyRule) ([]notifyRoute, error) {
+	routes := make([]notifyRoute, 0, len(rules))
+
+	for i, rule := range rules {
+		var n notifiers.Notifier
+		switch rule.Type {
+		case "webhook":
+			n = &notifiers.Webhook{URL: rule.URL, Secret: rule.Secret}
+		case "slack":
+			n = &notifiers.Slack{WebhookURL: rule.URL}
+		case "sns":
+			n = &notifiers.SNS{Client: sns.NewFromConfig(cfg), TopicARN: rule.TopicARN}
+		case "ses":
+			n = &notifiers.SES{Client: sesv2.NewFromConfig(cfg), From: rule.From, To: rule.To}
+		default:
+			return nil, fmt.Errorf("notify rule %d: unknown type %q", i, rule.Type)
+		}
+
+		domains := make(map[string]struct{}, len(rule.Domains))
+		for _, d := range rule.Domains {
+			domains[d] = struct{}{}
+		}
+
+		window := defaultDedupWindow
+		if rule.DedupWindowSeconds > 0 {
+			window = time.Duration(rule.DedupWindowSeconds) * time.Second
+		}
+
+		routes = append(routes, notifyRoute{
+			notifier: n,
+			domains:  domains,
+			dedup: &notifiers.Dedup{
+				S3:     s.s3,
+				Bucket: s.bucket,
+				Key:    fmt.Sprintf("notify-dedup/%d.json", i),
+				Window: window,
+			},
+		})
+	}
+
+	return routes, nil
+}
+
+// notifyMatch fans evt out to every configured sink whose Domains filter
+// covers matchedDomain, skipping sinks that have already been notified
+// about this fingerprint within their dedup window. Delivery errors are
+// logged, not returned -- a slow or broken sink must not stop ingestion.
+func (s *server) notifyMatch(ctx context.Context, lgr *slog.Logger, evt notifiers.MatchEvent, matchedDomain string) {
+	for _, route := range s.notifyRoutes {
+		if len(route.domains) > 0 {
+			if _, ok := route.domains[matchedDomain]; !ok {
+				continue
+			}
+		}
+
+		seen, err := route.dedup.Seen(ctx, evt.Fingerprint)
+		if err != nil {
+			lgr.Error("notify dedup check err", "err", err)
+		} else if seen {
+			continue
+		}
+
+		if err := route.notifier.Notify(ctx, evt); err != nil {
+			lgr.Error("notify err", "err", err, "san", evt.SANName)
+		}
+	}
+}
+
+// matchedDomainFromRule extracts the domain a "domain-<x>" matchStr refers
+// to, as returned by server.nameMatches. Pattern matches ("pattern-<re>")
+// have no corresponding domain and return "".
+func matchedDomainFromRule(matchStr string) string {
+	domain, ok := strings.CutPrefix(matchStr, "domain-")
+	if !ok {
+		return ""
+	}
+	return domain
+}
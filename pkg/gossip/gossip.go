@@ -0,0 +1,109 @@
+// Package gossip publishes every signed tree head cert-monitor-lambda has
+// verified for a log, in an append-only form, so a separate auditor -- or
+// another monitor watching the same log from elsewhere -- can diff what
+// this instance observed against its own view and catch a split view that
+// would otherwise only ever surface to one side of it.
+//
+// This only covers the "publish what we saw" half of STH gossip (also
+// called STH pollination). Actively fetching and cross-checking other
+// parties' published STHs isn't implemented here: doing that usefully needs
+// a registry of peer monitors to pull from, which this repo has no protocol
+// for yet, so it's left for a follow-up rather than bolted on speculatively.
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	ct "github.com/google/certificate-transparency-go"
+)
+
+// Entry is a single published observation of a log's tree head.
+type Entry struct {
+	LogURL         string    `json:"log_url"`
+	TreeSize       uint64    `json:"tree_size"`
+	SHA256RootHash string    `json:"sha256_root_hash"` // hex
+	ObservedAt     time.Time `json:"observed_at"`
+}
+
+const shardPrefix = "gossip/"
+
+// Store appends observed STHs to a per-log NDJSON shard in S3. Each log
+// gets its own shard (see shardKey), so unlike pkg/storage/index's shared
+// seen-set, concurrent Publish calls for different logs never touch the
+// same object and need no locking.
+type Store struct {
+	S3     *s3.Client
+	Bucket string
+}
+
+// shardKey returns a stable shard name for logURL. A log's URL can contain
+// characters that don't make a good S3 key, so we key on a hash of it
+// rather than the URL itself -- the same reason pkg/storage/index keys
+// certificates by fingerprint instead of by name.
+func shardKey(logURL string) string {
+	sum := sha256.Sum256([]byte(logURL))
+	return fmt.Sprintf("%s%x.ndjson", shardPrefix, sum[:8])
+}
+
+// Publish appends sth as a new observation of logURL's tree head.
+func (s *Store) Publish(ctx context.Context, logURL string, sth *ct.SignedTreeHead) error {
+	entry := Entry{
+		LogURL:         logURL,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: fmt.Sprintf("%x", sth.SHA256RootHash[:]),
+		ObservedAt:     time.Now(),
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal gossip entry err: %w", err)
+	}
+	line = append(line, '\n')
+
+	key := shardKey(logURL)
+	existing, err := s.getObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read gossip shard err: %w", err)
+	}
+
+	_, err = s.S3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(append(existing, line...)),
+	})
+	if err != nil {
+		return fmt.Errorf("put gossip shard err: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) getObject(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			// No shard yet is the normal starting state for a log we
+			// haven't published an observation for before.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get gossip shard err: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,46 @@
+package logclient
+
+import (
+	"context"
+	"net/http"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+)
+
+// RFC6962Client adapts the upstream jsonclient-based *client.LogClient to
+// the Client interface.
+type RFC6962Client struct {
+	lc *client.LogClient
+}
+
+// NewRFC6962 constructs a Client for a log that speaks the original
+// RFC 6962 get-sth / get-entries API.
+func NewRFC6962(logURL string, httpClient *http.Client) (*RFC6962Client, error) {
+	lc, err := client.New(logURL, httpClient, jsonclient.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &RFC6962Client{lc: lc}, nil
+}
+
+func (c *RFC6962Client) GetSTH(ctx context.Context) (*ct.SignedTreeHead, error) {
+	return c.lc.GetSTH(ctx)
+}
+
+func (c *RFC6962Client) GetSTHConsistency(ctx context.Context, first, second int64) ([][]byte, error) {
+	return c.lc.GetSTHConsistency(ctx, uint64(first), uint64(second))
+}
+
+// GetRawEntries honors the half-open [start, end) contract documented on
+// Client, translating it to the closed interval (start..end inclusive) that
+// RFC 6962's get-entries API, and so *client.LogClient.GetRawEntries, actually
+// expects.
+func (c *RFC6962Client) GetRawEntries(ctx context.Context, start, end int64) ([]ct.LeafEntry, error) {
+	resp, err := c.lc.GetRawEntries(ctx, start, end-1)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
@@ -0,0 +1,239 @@
+package logclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/transparency-dev/merkle/proof"
+)
+
+// tileWidth is the number of leaves (and the number of node hashes at any
+// given level) packed into a single tile or entry bundle file.
+const tileWidth = 256
+
+// StaticTileClient speaks the static-CT-API used by tile-based logs such as
+// Sunlight, TesseraCT and Azul: the log is a set of static files fetched
+// over plain HTTPS, usually via a CDN, rather than a dynamic JSON API.
+//
+//   - checkpoint    - a signed-note encoded tree head
+//   - tile/<L>/<N>  - the N'th tile of Merkle hashes at level L
+//   - tile/data/<N> - the N'th bundle of leaf entries
+//
+// This covers the read path cert-monitor-lambda needs: fetching the
+// checkpoint, computing a consistency proof from tile hashes, and walking
+// entry bundles. It does not implement log submission.
+type StaticTileClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewStaticTile constructs a Client for a log served at baseURL using the
+// static-CT-API tile layout.
+func NewStaticTile(baseURL string, httpClient *http.Client) *StaticTileClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &StaticTileClient{baseURL: strings.TrimRight(baseURL, "/"), client: httpClient}
+}
+
+func (c *StaticTileClient) fetch(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", path, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// checkpoint is the parsed form of a log's signed-note "checkpoint" file:
+//
+//	<origin>
+//	<tree size>
+//	<base64 root hash>
+//
+//	— <signer id> <base64 signature>
+type checkpoint struct {
+	origin    string
+	treeSize  uint64
+	rootHash  []byte
+	signature []byte
+}
+
+func parseCheckpoint(body []byte) (*checkpoint, error) {
+	lines := strings.Split(strings.TrimRight(string(body), "\n"), "\n")
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("malformed checkpoint: expected at least 4 lines, got %d", len(lines))
+	}
+
+	size, err := strconv.ParseUint(lines[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed checkpoint tree size %q: %w", lines[1], err)
+	}
+
+	root, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed checkpoint root hash: %w", err)
+	}
+
+	var sig []byte
+	for _, line := range lines[3:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "—" {
+			continue
+		}
+		sig, err = base64.StdEncoding.DecodeString(fields[len(fields)-1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed checkpoint signature: %w", err)
+		}
+		break
+	}
+
+	return &checkpoint{origin: lines[0], treeSize: size, rootHash: root, signature: sig}, nil
+}
+
+// GetSTH fetches and parses the log's checkpoint, translating it into the
+// same ct.SignedTreeHead shape RFC 6962 logs return so processLog can treat
+// both protocols identically. The checkpoint's note signature is over a
+// different encoding than RFC 6962's TreeHeadSignature, so it isn't carried
+// through here, and -- unlike verifySTHSignature's RFC 6962 check -- nothing
+// in this package verifies it against the log's published verifier key
+// either: cp.signature is parsed and discarded. processLog's verifyConsistency
+// call still checks each new checkpoint is consistent with the last one this
+// monitor saw, but that only catches a log changing its story over time, not
+// a CDN or MITM serving a self-consistent fabricated history from the start.
+// Static-CT logs currently get no cryptographic STH authentication at all.
+func (c *StaticTileClient) GetSTH(ctx context.Context) (*ct.SignedTreeHead, error) {
+	body, err := c.fetch(ctx, "checkpoint")
+	if err != nil {
+		return nil, fmt.Errorf("fetch checkpoint err: %w", err)
+	}
+
+	cp, err := parseCheckpoint(body)
+	if err != nil {
+		return nil, err
+	}
+
+	sth := &ct.SignedTreeHead{TreeSize: cp.treeSize}
+	copy(sth.SHA256RootHash[:], cp.rootHash)
+	return sth, nil
+}
+
+// GetSTHConsistency computes a consistency proof between tree sizes first
+// and second by fetching only the tile node hashes the standard algorithm
+// needs, rather than the whole tree.
+func (c *StaticTileClient) GetSTHConsistency(ctx context.Context, first, second int64) ([][]byte, error) {
+	nodes, err := proof.Consistency(uint64(first), uint64(second))
+	if err != nil {
+		return nil, fmt.Errorf("compute consistency node list err: %w", err)
+	}
+
+	hashes := make([][]byte, len(nodes.IDs))
+	for i, id := range nodes.IDs {
+		hash, err := c.tileNodeHash(ctx, int64(id.Level), int64(id.Index))
+		if err != nil {
+			return nil, fmt.Errorf("fetch consistency tile node level=%d index=%d err: %w", id.Level, id.Index, err)
+		}
+		hashes[i] = hash
+	}
+
+	return nodes.Rehash(hashes, func(a, b []byte) []byte {
+		h := sha256.Sum256(append(append([]byte{1}, a...), b...))
+		return h[:]
+	})
+}
+
+// tileNodeHash returns the hash at (level, index) in the Merkle tree by
+// fetching the tile that contains it and indexing into it.
+func (c *StaticTileClient) tileNodeHash(ctx context.Context, level, index int64) ([]byte, error) {
+	tileIndex := index / tileWidth
+	offsetInTile := index % tileWidth
+
+	body, err := c.fetch(ctx, fmt.Sprintf("tile/%d/%d", level, tileIndex))
+	if err != nil {
+		return nil, err
+	}
+
+	const hashSize = 32 // SHA-256
+	offset := int(offsetInTile) * hashSize
+	if offset+hashSize > len(body) {
+		return nil, fmt.Errorf("tile/%d/%d too short for index %d", level, tileIndex, index)
+	}
+
+	return body[offset : offset+hashSize], nil
+}
+
+// GetRawEntries walks entry-bundle files of tileWidth leaves each, covering
+// [start, end).
+func (c *StaticTileClient) GetRawEntries(ctx context.Context, start, end int64) ([]ct.LeafEntry, error) {
+	var entries []ct.LeafEntry
+
+	for i := start; i < end; {
+		bundleIndex := i / tileWidth
+		body, err := c.fetch(ctx, fmt.Sprintf("tile/data/%d", bundleIndex))
+		if err != nil {
+			return nil, fmt.Errorf("fetch entry bundle %d err: %w", bundleIndex, err)
+		}
+
+		bundle, err := parseEntryBundle(body)
+		if err != nil {
+			return nil, fmt.Errorf("parse entry bundle %d err: %w", bundleIndex, err)
+		}
+
+		bundleStart := bundleIndex * tileWidth
+		for leafIndex := i - bundleStart; leafIndex < int64(len(bundle)) && bundleStart+leafIndex < end; leafIndex++ {
+			entries = append(entries, bundle[leafIndex])
+			i++
+		}
+
+		if int64(len(bundle)) < tileWidth {
+			// Partial (final) bundle: nothing more to fetch past here.
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// parseEntryBundle splits a tile/data/<N> bundle into individual leaf
+// entries, each framed as a 3-byte big-endian length prefix followed by the
+// entry body -- the same framing RFC 6962's MerkleTreeLeaf uses, so the
+// rest of cert-monitor-lambda can decode both with ct.LogEntryFromLeaf.
+func parseEntryBundle(body []byte) ([]ct.LeafEntry, error) {
+	var entries []ct.LeafEntry
+	r := bytes.NewReader(body)
+
+	for r.Len() > 0 {
+		var lenBuf [3]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			return nil, fmt.Errorf("read entry length err: %w", err)
+		}
+		leafLen := int(lenBuf[0])<<16 | int(lenBuf[1])<<8 | int(lenBuf[2])
+
+		leaf := make([]byte, leafLen)
+		if _, err := io.ReadFull(r, leaf); err != nil {
+			return nil, fmt.Errorf("read entry body err: %w", err)
+		}
+
+		entries = append(entries, ct.LeafEntry{LeafInput: leaf})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,211 @@
+// Package monitor fetches ranges of CT log entries in fixed-size chunks
+// using a bounded worker pool, reassembling out-of-order results so callers
+// can treat a run as if the whole range were fetched sequentially.
+package monitor
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+)
+
+// DefaultChunkSize is the number of leaves requested per GetRawEntries call.
+// CT logs are free to return fewer entries than requested, so this is kept
+// well under the response caps seen in practice, trading a few extra round
+// trips for chunks that are (almost) always satisfied in full.
+const DefaultChunkSize = 256
+
+// FetchFunc retrieves raw leaf entries for the half-open range [start, end).
+type FetchFunc func(ctx context.Context, start, end int64) ([]ct.LeafEntry, error)
+
+// EntryFunc is invoked once per entry, in strictly increasing index order,
+// for every chunk that was fetched in full.
+type EntryFunc func(index int64, entry *ct.LeafEntry) error
+
+type chunk struct {
+	start, end int64
+}
+
+type chunkResult struct {
+	chunk   chunk
+	entries []ct.LeafEntry
+	err     error
+}
+
+// resultHeap orders completed chunks by start index so results that race
+// in out of order can be drained back into the order the log defines.
+type resultHeap []chunkResult
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return h[i].chunk.start < h[j].chunk.start }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *resultHeap) Push(x any) { *h = append(*h, x.(chunkResult)) }
+
+func (h *resultHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// Fetcher splits a range of log entries into fixed-size chunks and fetches
+// them concurrently with a bounded worker pool, retrying transient errors
+// with backoff.
+type Fetcher struct {
+	// ChunkSize is the number of leaves requested per chunk. Defaults to
+	// DefaultChunkSize if zero.
+	ChunkSize int64
+
+	// Workers bounds how many chunks are fetched concurrently. Defaults to
+	// 4 if zero.
+	Workers int
+
+	// MaxRetries bounds how many times a single chunk is retried before its
+	// error halts the run. Defaults to 3 if zero.
+	MaxRetries int
+}
+
+// Run fetches [start, end) in chunks, calling onEntry for every entry in
+// strictly increasing index order. It stops dispatching new chunks once ctx
+// is done -- callers are expected to derive ctx's deadline from the
+// remaining time budget of the Lambda invocation, so a run that is about to
+// time out simply stops early rather than getting billed for work it can't
+// finish.
+//
+// Run returns the index immediately after the last contiguous,
+// successfully-processed chunk; the entry just before it is the one the
+// caller persists as LastFetched so the next invocation resumes exactly
+// where this one left off. That index may be less than end if time ran out
+// or a chunk permanently failed. When the chunk at that index failed after
+// its retries, came back short, or onEntry returned an error for one of its
+// entries, Run also returns a non-nil error describing it; the returned
+// index is valid either way.
+func (f *Fetcher) Run(ctx context.Context, start, end int64, fetch FetchFunc, onEntry EntryFunc) (int64, error) {
+	if start >= end {
+		return start, nil
+	}
+
+	chunkSize := f.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	workers := f.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	maxRetries := f.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks := make(chan chunk)
+	results := make(chan chunkResult)
+
+	go func() {
+		defer close(chunks)
+		for s := start; s < end; s += chunkSize {
+			e := s + chunkSize
+			if e > end {
+				e = end
+			}
+			select {
+			case chunks <- chunk{start: s, end: e}:
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				entries, err := fetchWithRetry(runCtx, fetch, c, maxRetries)
+				select {
+				case results <- chunkResult{chunk: c, entries: entries, err: err}:
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := &resultHeap{}
+	heap.Init(pending)
+	next := start
+	var runErr error
+
+drain:
+	for res := range results {
+		heap.Push(pending, res)
+
+		for pending.Len() > 0 && (*pending)[0].chunk.start == next {
+			top := heap.Pop(pending).(chunkResult)
+
+			if top.err != nil {
+				runErr = fmt.Errorf("fetch chunk [%d,%d) err: %w", top.chunk.start, top.chunk.end, top.err)
+				cancel()
+				break drain
+			}
+			if int64(len(top.entries)) != top.chunk.end-top.chunk.start {
+				runErr = fmt.Errorf("fetch chunk [%d,%d) short: got %d entries", top.chunk.start, top.chunk.end, len(top.entries))
+				cancel()
+				break drain
+			}
+
+			for i := range top.entries {
+				index := top.chunk.start + int64(i)
+				if err := onEntry(index, &top.entries[i]); err != nil {
+					runErr = fmt.Errorf("process entry %d err: %w", index, err)
+					cancel()
+					break drain
+				}
+			}
+			next = top.chunk.end
+		}
+	}
+
+	// Unblock any workers still trying to send once we've stopped reading.
+	for range results {
+	}
+
+	return next, runErr
+}
+
+func fetchWithRetry(ctx context.Context, fetch FetchFunc, c chunk, maxRetries int) ([]ct.LeafEntry, error) {
+	var lastErr error
+	backoff := 250 * time.Millisecond
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		entries, err := fetch(ctx, c.start, c.end)
+		if err == nil {
+			return entries, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	ct "github.com/google/certificate-transparency-go"
+)
+
+// closedIntervalSource simulates an upstream whose entries API is a closed
+// interval (start..end inclusive), the way RFC 6962's get-entries works, and
+// returns it as a FetchFunc by subtracting one from end -- the same
+// translation RFC6962Client.GetRawEntries applies in front of the real
+// *client.LogClient.
+func closedIntervalSource(total int64) FetchFunc {
+	closed := func(start, end int64) []ct.LeafEntry {
+		var entries []ct.LeafEntry
+		for i := start; i <= end && i < total; i++ {
+			entries = append(entries, ct.LeafEntry{LeafInput: []byte{byte(i)}})
+		}
+		return entries
+	}
+
+	return func(ctx context.Context, start, end int64) ([]ct.LeafEntry, error) {
+		return closed(start, end-1), nil
+	}
+}
+
+func TestFetcherRunMultiChunkClosedInterval(t *testing.T) {
+	const total = 10
+
+	fetcher := &Fetcher{ChunkSize: 3, Workers: 2}
+
+	var got []int64
+	reached, err := fetcher.Run(context.Background(), 0, total, closedIntervalSource(total), func(index int64, entry *ct.LeafEntry) error {
+		got = append(got, index)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run err: %v", err)
+	}
+	if reached != total {
+		t.Fatalf("reached = %d, want %d", reached, total)
+	}
+
+	if len(got) != total {
+		t.Fatalf("got %d entries, want %d", len(got), total)
+	}
+	for i, idx := range got {
+		if idx != int64(i) {
+			t.Fatalf("entries out of order: got[%d] = %d, want %d", i, idx, i)
+		}
+	}
+}
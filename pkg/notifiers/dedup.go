@@ -0,0 +1,103 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Dedup suppresses repeat notifications for the same certificate
+// fingerprint within Window, tracked via a small JSON object in S3. This
+// keeps re-ingested logs (the same cert logged more than once, or logs that
+// cross-submit to each other) from notifying a sink more than once for the
+// same cert. A precert and its final cert have different fingerprints, so
+// each is notified on its own.
+//
+// A single Dedup is shared across every per-log goroutine cert-monitor-lambda
+// spawns (one per [[notify]] rule, not per log), so mu serializes Seen's
+// load/mutate/save cycle against S3 the same way pkg/storage/index.Index
+// does for its own shared state.
+type Dedup struct {
+	S3     *s3.Client
+	Bucket string
+	Key    string
+	Window time.Duration
+
+	mu sync.Mutex
+}
+
+type dedupState map[string]time.Time
+
+// Seen reports whether fingerprint was already notified within Window, and
+// records it as seen if not.
+func (d *Dedup) Seen(ctx context.Context, fingerprint string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, err := d.load(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-d.Window)
+	for fp, seenAt := range state {
+		if seenAt.Before(cutoff) {
+			delete(state, fp)
+		}
+	}
+
+	if seenAt, ok := state[fingerprint]; ok && seenAt.After(cutoff) {
+		return true, nil
+	}
+
+	state[fingerprint] = now
+	return false, d.save(ctx, state)
+}
+
+func (d *Dedup) load(ctx context.Context) (dedupState, error) {
+	resp, err := d.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &d.Bucket,
+		Key:    &d.Key,
+	})
+	if err != nil {
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			// No existing dedup object is the normal starting state, same
+			// as cert-monitor-lambda's own log-state.json handling.
+			return make(dedupState), nil
+		}
+		return nil, fmt.Errorf("get dedup state err: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var state dedupState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("decode dedup state err: %w", err)
+	}
+	return state, nil
+}
+
+func (d *Dedup) save(ctx context.Context, state dedupState) error {
+	body, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal dedup state err: %w", err)
+	}
+
+	_, err = d.S3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &d.Bucket,
+		Key:    &d.Key,
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("put dedup state err: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package notifiers delivers certificate match events to external sinks
+// (webhooks, Slack, SNS, SES) so operators can learn about a match without
+// polling the bucket cert-monitor-lambda writes to.
+package notifiers
+
+import (
+	"context"
+	"time"
+)
+
+// MatchEvent describes a certificate that matched a configured domain or
+// pattern, for delivery to one or more notification sinks.
+type MatchEvent struct {
+	LogURL      string    `json:"log_url"`
+	MatchedRule string    `json:"matched_rule"`
+	SANName     string    `json:"san_name"`
+	CertType    string    `json:"cert_type"` // "cert" or "precert"
+	Fingerprint string    `json:"fingerprint"`
+	S3Key       string    `json:"s3_key"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// Notifier delivers a MatchEvent to an external sink. Notify is called
+// synchronously from the entry-processing loop, so implementations should
+// fail fast rather than blocking ingestion of the rest of the log.
+type Notifier interface {
+	Notify(ctx context.Context, evt MatchEvent) error
+}
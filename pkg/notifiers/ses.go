@@ -0,0 +1,47 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// SES emails the MatchEvent to a fixed set of recipients via Amazon SES v2.
+type SES struct {
+	Client *sesv2.Client
+	From   string
+	To     []string
+}
+
+func (n *SES) Notify(ctx context.Context, evt MatchEvent) error {
+	body, err := json.MarshalIndent(evt, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal match event err: %w", err)
+	}
+
+	subject := fmt.Sprintf("cert-monitor match: %s", evt.SANName)
+
+	_, err = n.Client.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(n.From),
+		Destination: &types.Destination{
+			ToAddresses: n.To,
+		},
+		Content: &types.EmailContent{
+			Simple: &types.Message{
+				Subject: &types.Content{Data: aws.String(subject)},
+				Body: &types.Body{
+					Text: &types.Content{Data: aws.String(string(body))},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("ses send email err: %w", err)
+	}
+
+	return nil
+}
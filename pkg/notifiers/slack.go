@@ -0,0 +1,52 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Slack posts a short, human-readable message to a Slack incoming webhook
+// URL.
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func (sl *Slack) Notify(ctx context.Context, evt MatchEvent) error {
+	msg := struct {
+		Text string `json:"text"`
+	}{
+		Text: fmt.Sprintf("cert-monitor match: `%s` (%s) rule=%s log=%s cert=%s", evt.SANName, evt.CertType, evt.MatchedRule, evt.LogURL, evt.S3Key),
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal slack message err: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sl.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new slack request err: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := sl.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request err: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// SNS publishes the MatchEvent as a JSON message to an SNS topic.
+type SNS struct {
+	Client   *sns.Client
+	TopicARN string
+}
+
+func (n *SNS) Notify(ctx context.Context, evt MatchEvent) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("marshal match event err: %w", err)
+	}
+
+	_, err = n.Client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(n.TopicARN),
+		Message:  aws.String(string(body)),
+		Subject:  aws.String(fmt.Sprintf("cert-monitor match: %s", evt.SANName)),
+	})
+	if err != nil {
+		return fmt.Errorf("sns publish err: %w", err)
+	}
+
+	return nil
+}
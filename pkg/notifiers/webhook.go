@@ -0,0 +1,57 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Webhook POSTs the MatchEvent as JSON to a configured URL. If Secret is
+// set, the body is signed with HMAC-SHA256 and the signature is sent in the
+// X-Cert-Monitor-Signature header so the receiver can authenticate us.
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, evt MatchEvent) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("marshal match event err: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new webhook request err: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Cert-Monitor-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request err: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
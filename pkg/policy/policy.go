@@ -0,0 +1,170 @@
+// Package policy implements a label-aware domain matcher for certificate
+// SANs. It replaces a plain strings.HasSuffix comparison, which can't tell
+// "evil-example.com" from a subdomain of "example.com" without a careful
+// extra guard, and can't express wildcard or exclusion rules at all.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// MatchResult records that a certificate SAN matched a configured rule.
+type MatchResult struct {
+	Rule string // the configured pattern, e.g. "*.corp.example.com"
+	SAN  string // the SAN value that matched
+	Type string // "dns", "ip", or "uri"
+}
+
+// Policy evaluates a certificate's SANs against a set of domain patterns.
+type Policy struct {
+	rules []rule
+}
+
+type rule struct {
+	raw    string
+	negate bool
+	labels []string
+}
+
+// New parses patterns into a Policy. Each pattern is a dot-separated
+// sequence of DNS labels, e.g. "example.com", "*.corp.example.com", or
+// "foo.*.example.com". A bare pattern with no "*" label, like "example.com",
+// matches itself and every subdomain of it, including "foo.example.com" and
+// "a.b.example.com". A pattern with a "*" label instead matches exactly one
+// arbitrary label at that position, with every other label matching
+// literally (RFC 6125 wildcard semantics extended to any label position, not
+// just the leftmost), so "*.example.com" matches "foo.example.com" but not
+// "example.com" itself or "a.b.example.com", and "foo.*.example.com" matches
+// "foo.bar.example.com" but not "foo.example.com" or "foo.bar.baz.example.com".
+// A pattern prefixed with "!" excludes any SAN it would otherwise match, even
+// if another rule also matches it.
+func New(patterns []string) (*Policy, error) {
+	p := &Policy{rules: make([]rule, 0, len(patterns))}
+	for _, raw := range patterns {
+		r, err := parseRule(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", raw, err)
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+func parseRule(raw string) (rule, error) {
+	pattern := raw
+	negate := false
+	if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+		negate = true
+		pattern = rest
+	}
+	if pattern == "" {
+		return rule{}, fmt.Errorf("empty pattern")
+	}
+
+	return rule{
+		raw:    raw,
+		negate: negate,
+		labels: strings.Split(strings.ToLower(pattern), "."),
+	}, nil
+}
+
+// Match returns every SAN (DNS, IP, URI) in cert that matches at least one
+// non-negated rule and isn't excluded by a negated one.
+func (p *Policy) Match(cert *x509.Certificate) []MatchResult {
+	var results []MatchResult
+
+	for _, name := range cert.DNSNames {
+		if raw, ok := p.bestMatch(name); ok {
+			results = append(results, MatchResult{Rule: raw, SAN: name, Type: "dns"})
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		name := ip.String()
+		if raw, ok := p.bestMatch(name); ok {
+			results = append(results, MatchResult{Rule: raw, SAN: name, Type: "ip"})
+		}
+	}
+	for _, uri := range cert.URIs {
+		name := uri.String()
+		if raw, ok := p.bestMatch(name); ok {
+			results = append(results, MatchResult{Rule: raw, SAN: name, Type: "uri"})
+		}
+	}
+
+	return results
+}
+
+// bestMatch reports the first non-negated rule matching name, unless a
+// negated rule also matches it, in which case name is excluded entirely.
+func (p *Policy) bestMatch(name string) (string, bool) {
+	nameLabels := strings.Split(strings.ToLower(name), ".")
+
+	var raw string
+	var ok bool
+	for _, r := range p.rules {
+		if !labelsMatch(r.labels, nameLabels) {
+			continue
+		}
+		if r.negate {
+			return "", false
+		}
+		if !ok {
+			raw, ok = r.raw, true
+		}
+	}
+	return raw, ok
+}
+
+// labelsMatch reports whether name satisfies pattern. A "*" label in pattern,
+// at any position, matches exactly one arbitrary label there, with every
+// other label required to match literally -- so a wildcard pattern always
+// requires the same label count as name. A pattern with no "*" label instead
+// matches itself and any subdomain of it -- "example.com" matches both
+// "example.com" and "foo.example.com" -- since that's the documented, and
+// most common, way to monitor a domain.
+func labelsMatch(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return false
+	}
+
+	if hasWildcard(pattern) {
+		if len(name) != len(pattern) {
+			return false
+		}
+		for i, label := range pattern {
+			if label != "*" && label != name[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(name) < len(pattern) {
+		return false
+	}
+	return labelsEqual(pattern, name[len(name)-len(pattern):])
+}
+
+func hasWildcard(labels []string) bool {
+	for _, label := range labels {
+		if label == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func labelsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+// Package index maintains a compact, append-only record of every
+// certificate cert-monitor-lambda has emitted, keyed by the SHA-256 of the
+// DER-encoded certificate. This lets the monitor skip duplicates that show
+// up across multiple CT logs -- the same precert or final cert logged more
+// than once, and logs that cross-submit to each other -- and gives operators
+// a real audit trail to query without standing up a separate database. A
+// precert is keyed by its TBSCertificate bytes and a final cert by its full
+// DER, so a precert and its matching final cert are separate entries and are
+// not deduplicated against each other.
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Record is a single entry in the index: enough metadata to answer "have we
+// seen this cert, and where is the full JSON for it" without fetching every
+// certificate back out of S3.
+type Record struct {
+	Fingerprint string    `json:"fingerprint"` // hex sha256 of the DER cert
+	Domain      string    `json:"domain"`      // the SAN that matched
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	LogURL      string    `json:"log_url"`
+	S3Key       string    `json:"s3_key"` // full cert JSON under certs/
+	Time        time.Time `json:"time"`   // when cert-monitor-lambda observed it
+}
+
+// Filter narrows a Query to records matching all of its non-zero fields.
+type Filter struct {
+	Domain string // substring match against Record.Domain
+	Issuer string // substring match against Record.Issuer
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f Filter) matches(r Record) bool {
+	if f.Domain != "" && !strings.Contains(r.Domain, f.Domain) {
+		return false
+	}
+	if f.Issuer != "" && !strings.Contains(r.Issuer, f.Issuer) {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+const (
+	shardPrefix = "index/"
+	seenKey     = "index/seen.json"
+)
+
+// Index is an append-only record of every certificate cert-monitor-lambda
+// has emitted, stored as NDJSON objects in the same S3 bucket as everything
+// else, sharded by UTC date so no single object grows without bound.
+//
+// cert-monitor-lambda processes every CT log concurrently, so Seen and
+// Record can be called from many goroutines against the same *Index at
+// once; mu serializes the get-whole-object/mutate/put-whole-object cycle
+// both methods do against S3, since S3 has no compare-and-swap primitive to
+// do that safely without an in-process lock.
+type Index struct {
+	S3     *s3.Client
+	Bucket string
+
+	mu sync.Mutex
+}
+
+func shardKey(t time.Time) string {
+	return fmt.Sprintf("%s%s.ndjson", shardPrefix, t.UTC().Format("2006-01-02"))
+}
+
+// Seen reports whether fingerprint has already been recorded in the index.
+func (ix *Index) Seen(ctx context.Context, fingerprint string) (bool, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	seen, err := ix.loadSeen(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := seen[fingerprint]
+	return ok, nil
+}
+
+// Reserve atomically checks whether fingerprint has already been recorded
+// and, if not, marks it seen immediately, before returning. Callers that
+// need to do expensive work (writing the cert, notifying sinks) only when a
+// fingerprint is new must use Reserve instead of a separate Seen followed
+// later by Record: two goroutines racing on the same fingerprint -- the
+// same certificate landing in different logs around the same time is the
+// common case -- could otherwise both see Seen return
+// false before either called Record, and both do that work.
+func (ix *Index) Reserve(ctx context.Context, fingerprint string) (alreadySeen bool, err error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	seen, err := ix.loadSeen(ctx)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := seen[fingerprint]; ok {
+		return true, nil
+	}
+
+	seen[fingerprint] = time.Now()
+	if err := ix.saveSeen(ctx, seen); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Record appends rec to its day's shard and marks its fingerprint seen. It
+// is safe to call concurrently against the same Index: mu serializes the
+// read-modify-write against both the day's shard and the shared seen set.
+func (ix *Index) Record(ctx context.Context, rec Record) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal index record err: %w", err)
+	}
+	line = append(line, '\n')
+
+	key := shardKey(rec.Time)
+	existing, err := ix.getObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read index shard err: %w", err)
+	}
+
+	_, err = ix.S3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &ix.Bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(append(existing, line...)),
+	})
+	if err != nil {
+		return fmt.Errorf("put index shard err: %w", err)
+	}
+
+	seen, err := ix.loadSeen(ctx)
+	if err != nil {
+		return err
+	}
+	seen[rec.Fingerprint] = rec.Time
+	return ix.saveSeen(ctx, seen)
+}
+
+// Query returns every indexed record matching filter, across all shards.
+func (ix *Index) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	var records []Record
+
+	paginator := s3.NewListObjectsV2Paginator(ix.S3, &s3.ListObjectsV2Input{
+		Bucket: &ix.Bucket,
+		Prefix: aws.String(shardPrefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list index shards err: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			if obj.Key == nil || *obj.Key == seenKey {
+				continue
+			}
+
+			body, err := ix.getObject(ctx, *obj.Key)
+			if err != nil {
+				return nil, fmt.Errorf("read index shard %s err: %w", *obj.Key, err)
+			}
+
+			scanner := bufio.NewScanner(bytes.NewReader(body))
+			for scanner.Scan() {
+				var rec Record
+				if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+					return nil, fmt.Errorf("decode index record in %s err: %w", *obj.Key, err)
+				}
+				if filter.matches(rec) {
+					records = append(records, rec)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("scan index shard %s err: %w", *obj.Key, err)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+func (ix *Index) getObject(ctx context.Context, key string) ([]byte, error) {
+	resp, err := ix.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &ix.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			// A missing object is the normal starting state, same as
+			// cert-monitor-lambda's own log-state.json handling.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get index object %s err: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (ix *Index) loadSeen(ctx context.Context) (map[string]time.Time, error) {
+	body, err := ix.getObject(ctx, seenKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return make(map[string]time.Time), nil
+	}
+
+	var seen map[string]time.Time
+	if err := json.Unmarshal(body, &seen); err != nil {
+		return nil, fmt.Errorf("decode seen set err: %w", err)
+	}
+	return seen, nil
+}
+
+func (ix *Index) saveSeen(ctx context.Context, seen map[string]time.Time) error {
+	body, err := json.Marshal(seen)
+	if err != nil {
+		return fmt.Errorf("marshal seen set err: %w", err)
+	}
+
+	_, err = ix.S3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &ix.Bucket,
+		Key:    aws.String(seenKey),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("put seen set err: %w", err)
+	}
+	return nil
+}